@@ -25,7 +25,7 @@ func EmbedTexts(
 	}
 
 	if model.Service == "local" {
-		return embedTextsLocal(ctx, appState, documentType, text)
+		return embedTextsLocal(ctx, model, text)
 	}
 	return embeddingsClient.EmbedTexts(ctx, text)
 }
@@ -40,12 +40,14 @@ func GetEmbeddingModel(
 		return &models.EmbeddingModel{
 			Service:    config.Service,
 			Dimensions: config.Dimensions,
+			Local:      config.Local,
 		}, nil
 	case "document":
 		config := appState.Config.Extractors.Documents.Embeddings
 		return &models.EmbeddingModel{
 			Service:    config.Service,
 			Dimensions: config.Dimensions,
+			Local:      config.Local,
 		}, nil
 	default:
 		return nil, errors.New("invalid document type")
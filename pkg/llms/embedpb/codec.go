@@ -0,0 +1,32 @@
+package embedpb
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// JSONCodecName is the gRPC content-subtype this package's client and
+// server negotiate, in place of protobuf's default "proto" codec.
+const JSONCodecName = "json"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec implements encoding.Codec with encoding/json, so EmbedRequest
+// and EmbedResponse can travel over gRPC without generated protobuf
+// marshaling code.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return JSONCodecName
+}
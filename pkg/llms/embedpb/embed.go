@@ -0,0 +1,32 @@
+// Package embedpb defines the messages and gRPC service shared between Zep
+// and the sentence-transformers embedding sidecar (see sidecar/server.py).
+//
+// embed.proto documents the wire contract, but this package is hand-
+// maintained rather than produced by protoc: the types below are plain Go
+// structs, and the Embedder service (service.go) is registered against a
+// JSON codec (codec.go) instead of generated protobuf marshaling code. That
+// keeps pkg/llms buildable without a protoc/protoc-gen-go toolchain step,
+// at the cost of the wire format being JSON-over-gRPC rather than binary
+// protobuf -- fine for an internal sidecar where both ends are maintained
+// together. Projects that prefer real protobuf codegen can regenerate from
+// embed.proto and drop this file.
+package embedpb
+
+// EmbedRequest is the request message for Embedder.Embed.
+type EmbedRequest struct {
+	Texts []string `json:"texts"`
+}
+
+// Embedding is a single embedding vector.
+type Embedding struct {
+	Values []float32 `json:"values"`
+}
+
+// EmbedResponse is the response message for Embedder.Embed.
+type EmbedResponse struct {
+	Embeddings []*Embedding `json:"embeddings"`
+	// Dimensions is the length of every vector in Embeddings, echoed back
+	// so the caller can validate it against its own configured expectation
+	// without inspecting the first row.
+	Dimensions int32 `json:"dimensions"`
+}
@@ -0,0 +1,85 @@
+package embedpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const embedderServiceName = "embedpb.Embedder"
+
+// EmbedderClient is the client API for the Embedder service.
+type EmbedderClient interface {
+	Embed(ctx context.Context, in *EmbedRequest, opts ...grpc.CallOption) (*EmbedResponse, error)
+}
+
+type embedderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewEmbedderClient returns an EmbedderClient backed by cc.
+func NewEmbedderClient(cc grpc.ClientConnInterface) EmbedderClient {
+	return &embedderClient{cc: cc}
+}
+
+func (c *embedderClient) Embed(
+	ctx context.Context,
+	in *EmbedRequest,
+	opts ...grpc.CallOption,
+) (*EmbedResponse, error) {
+	opts = append(opts, grpc.CallContentSubtype(JSONCodecName))
+	out := new(EmbedResponse)
+	if err := c.cc.Invoke(ctx, "/"+embedderServiceName+"/Embed", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EmbedderServer is the server API a sidecar implements for the Embedder
+// service. The Python reference sidecar (sidecar/server.py) implements the
+// same RPC directly against grpc's generic handler rather than this
+// interface, but a Go-hosted sidecar would implement it.
+type EmbedderServer interface {
+	Embed(context.Context, *EmbedRequest) (*EmbedResponse, error)
+}
+
+// RegisterEmbedderServer registers srv's Embed method on s.
+func RegisterEmbedderServer(s grpc.ServiceRegistrar, srv EmbedderServer) {
+	s.RegisterService(&embedderServiceDesc, srv)
+}
+
+func embedderEmbedHandler(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	in := new(EmbedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmbedderServer).Embed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + embedderServiceName + "/Embed",
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(EmbedderServer).Embed(ctx, req.(*EmbedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var embedderServiceDesc = grpc.ServiceDesc{
+	ServiceName: embedderServiceName,
+	HandlerType: (*EmbedderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Embed",
+			Handler:    embedderEmbedHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "embed.proto",
+}
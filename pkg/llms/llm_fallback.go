@@ -0,0 +1,219 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// RetryableLLMError marks an LLMError whose underlying failure -- a 429 or
+// a 5xx -- should cause FallbackLLM to fail over to the next configured
+// provider rather than returning the error to the caller. Timeouts are not
+// wrapped in it; shouldFailover recognizes them as context.DeadlineExceeded.
+type RetryableLLMError struct {
+	*LLMError
+	StatusCode int
+}
+
+// NewRetryableLLMError wraps message/originalError as an LLMError that
+// FallbackLLM treats as eligible for failover.
+func NewRetryableLLMError(message string, statusCode int, originalError error) *RetryableLLMError {
+	return &RetryableLLMError{
+		LLMError:   NewLLMError(message, originalError),
+		StatusCode: statusCode,
+	}
+}
+
+// fallbackChainKey is the context key marking a provider client as being
+// built as one link in a configured FallbackLLM chain, rather than as the
+// sole configured provider.
+type fallbackChainKey struct{}
+
+// withFallbackChain marks ctx as belonging to a FallbackLLM's per-provider
+// construction, so NewRetryableHTTPClient can use a fast, uniform 429
+// backoff -- exhausting this provider's retry budget quickly only helps
+// when there's a next provider to fail over to.
+func withFallbackChain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fallbackChainKey{}, true)
+}
+
+// inFallbackChain reports whether ctx was marked by withFallbackChain.
+func inFallbackChain(ctx context.Context) bool {
+	v, _ := ctx.Value(fallbackChainKey{}).(bool)
+	return v
+}
+
+// shouldFailover reports whether err represents the kind of provider-level
+// failure (rate limit, server error, timeout) that should be retried
+// against the next configured provider rather than surfaced to the caller.
+func shouldFailover(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var retryable *RetryableLLMError
+	if errors.As(err, &retryable) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+var (
+	llmProviderLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "zep_llm_provider_latency_seconds",
+			Help: "Latency of FallbackLLM calls to each configured provider.",
+		},
+		[]string{"provider", "outcome"},
+	)
+	llmProviderTokens = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "zep_llm_provider_tokens_total",
+			Help: "Tokens produced by each configured LLM provider.",
+		},
+		[]string{"provider"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(llmProviderLatency, llmProviderTokens)
+}
+
+// FallbackLLM is a composite models.ZepLLM that wraps an ordered list of
+// provider configs -- the primary cfg.LLM followed by cfg.LLM.Fallbacks --
+// and fails over to the next provider when one returns a retryable error.
+// Per-provider latency and outcome are recorded to Prometheus so operators
+// can see failover in action.
+type FallbackLLM struct {
+	providers []models.ZepLLM
+	names     []string
+}
+
+// NewFallbackLLM builds a FallbackLLM from cfg.LLM and cfg.LLM.Fallbacks, in
+// order. cfg.LLM.Fallbacks may be empty, in which case the returned
+// FallbackLLM behaves like a single-provider client.
+func NewFallbackLLM(ctx context.Context, cfg *config.Config) (*FallbackLLM, error) {
+	configs := make([]*config.LLM, 0, 1+len(cfg.LLM.Fallbacks))
+	configs = append(configs, cfg.LLM)
+	for i := range cfg.LLM.Fallbacks {
+		configs = append(configs, &cfg.LLM.Fallbacks[i])
+	}
+
+	fb := &FallbackLLM{
+		providers: make([]models.ZepLLM, 0, len(configs)),
+		names:     make([]string, 0, len(configs)),
+	}
+
+	for _, llmConfig := range configs {
+		// Strip Fallbacks on the per-provider config: each entry here
+		// builds a single concrete client, not another FallbackLLM.
+		providerLLMConfig := *llmConfig
+		providerLLMConfig.Fallbacks = nil
+
+		providerCfg := *cfg
+		providerCfg.LLM = &providerLLMConfig
+
+		client, err := NewLLMClient(withFallbackChain(ctx), &providerCfg)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"unable to build LLM provider %q/%q: %w",
+				llmConfig.Service,
+				llmConfig.Model,
+				err,
+			)
+		}
+
+		fb.providers = append(fb.providers, client)
+		fb.names = append(fb.names, fmt.Sprintf("%s/%s", llmConfig.Service, llmConfig.Model))
+	}
+
+	return fb, nil
+}
+
+func (fb *FallbackLLM) Call(ctx context.Context,
+	prompt string,
+	options ...llms.CallOption,
+) (string, error) {
+	var lastErr error
+
+	for i, provider := range fb.providers {
+		name := fb.names[i]
+		start := time.Now()
+
+		result, err := provider.Call(ctx, prompt, options...)
+		if err != nil {
+			llmProviderLatency.WithLabelValues(name, "error").Observe(time.Since(start).Seconds())
+			if shouldFailover(err) && i < len(fb.providers)-1 {
+				lastErr = err
+				continue
+			}
+			return "", err
+		}
+
+		llmProviderLatency.WithLabelValues(name, "success").Observe(time.Since(start).Seconds())
+		if tokens, tokErr := provider.GetTokenCount(result); tokErr == nil {
+			llmProviderTokens.WithLabelValues(name).Add(float64(tokens))
+		}
+
+		return result, nil
+	}
+
+	return "", NewLLMError("all configured LLM providers failed", lastErr)
+}
+
+func (fb *FallbackLLM) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	var lastErr error
+
+	for i, provider := range fb.providers {
+		name := fb.names[i]
+		start := time.Now()
+
+		embeddings, err := provider.EmbedTexts(ctx, texts)
+		if err != nil {
+			llmProviderLatency.WithLabelValues(name, "error").Observe(time.Since(start).Seconds())
+			if shouldFailover(err) && i < len(fb.providers)-1 {
+				lastErr = err
+				continue
+			}
+			return nil, err
+		}
+
+		llmProviderLatency.WithLabelValues(name, "success").Observe(time.Since(start).Seconds())
+		return embeddings, nil
+	}
+
+	return nil, NewLLMError("all configured LLM providers failed", lastErr)
+}
+
+// GetTokenCount defers to the primary provider, since token counts are
+// used for prompt-budgeting decisions made before a provider is chosen.
+func (fb *FallbackLLM) GetTokenCount(text string) (int, error) {
+	return fb.providers[0].GetTokenCount(text)
+}
+
+// statusCodeFailoverError classifies an HTTP response as retryable for the
+// purposes of FallbackLLM, wrapping it as a RetryableLLMError when it is.
+// Provider clients that make their own HTTP requests (e.g. ZepZhiPuAILLM)
+// can use this to participate in failover without depending on the
+// langchaingo/openai client's internal error handling.
+func statusCodeFailoverError(message string, resp *http.Response, originalError error) error {
+	if resp == nil {
+		return NewLLMError(message, originalError)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return NewRetryableLLMError(message, resp.StatusCode, originalError)
+	default:
+		return NewLLMError(message, originalError)
+	}
+}
@@ -0,0 +1,155 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+// fakeZepLLM is a minimal models.ZepLLM test double whose Call/EmbedTexts
+// results are configured per test.
+type fakeZepLLM struct {
+	callResult string
+	callErr    error
+
+	embedResult [][]float32
+	embedErr    error
+
+	tokenCount int
+}
+
+var _ models.ZepLLM = &fakeZepLLM{}
+
+func (f *fakeZepLLM) Call(_ context.Context, _ string, _ ...llms.CallOption) (string, error) {
+	return f.callResult, f.callErr
+}
+
+func (f *fakeZepLLM) EmbedTexts(_ context.Context, _ []string) ([][]float32, error) {
+	return f.embedResult, f.embedErr
+}
+
+func (f *fakeZepLLM) GetTokenCount(_ string) (int, error) {
+	return f.tokenCount, nil
+}
+
+func TestShouldFailover(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil error", nil, false},
+		{"retryable error", NewRetryableLLMError("rate limited", 429, nil), true},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"plain error", errors.New("boom"), false},
+		{"non-retryable LLMError", NewLLMError("invalid model", nil), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, shouldFailover(tt.err))
+		})
+	}
+}
+
+func TestFallbackLLMCallFailsOverToNextProvider(t *testing.T) {
+	primary := &fakeZepLLM{callErr: NewRetryableLLMError("rate limited", 429, nil)}
+	secondary := &fakeZepLLM{callResult: "from secondary", tokenCount: 3}
+
+	fb := &FallbackLLM{
+		providers: []models.ZepLLM{primary, secondary},
+		names:     []string{"openai/gpt-4", "anthropic/claude-instant-1"},
+	}
+
+	result, err := fb.Call(context.Background(), "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "from secondary", result)
+}
+
+func TestFallbackLLMCallReturnsImmediatelyOnNonRetryableError(t *testing.T) {
+	wantErr := errors.New("invalid request")
+	primary := &fakeZepLLM{callErr: wantErr}
+	secondary := &fakeZepLLM{callResult: "should not be reached"}
+
+	fb := &FallbackLLM{
+		providers: []models.ZepLLM{primary, secondary},
+		names:     []string{"openai/gpt-4", "anthropic/claude-instant-1"},
+	}
+
+	_, err := fb.Call(context.Background(), "hello")
+	assert.ErrorIs(t, err, wantErr)
+}
+
+func TestFallbackLLMCallReturnsErrorWhenAllProvidersFail(t *testing.T) {
+	primary := &fakeZepLLM{callErr: NewRetryableLLMError("rate limited", 429, nil)}
+	secondary := &fakeZepLLM{callErr: NewRetryableLLMError("server error", 503, nil)}
+
+	fb := &FallbackLLM{
+		providers: []models.ZepLLM{primary, secondary},
+		names:     []string{"openai/gpt-4", "anthropic/claude-instant-1"},
+	}
+
+	_, err := fb.Call(context.Background(), "hello")
+	assert.Error(t, err)
+}
+
+// TestZepOpenAILLMCallTimeoutIsFailoverEligible exercises the real wrapping
+// a ctx timeout goes through -- NewOpenAILLM -> Call -> transport.classify
+// -- rather than asserting on context.DeadlineExceeded directly, to catch
+// exactly the kind of break TestShouldFailover's synthetic case can't:
+// LLMError needs an Unwrap() for errors.Is to see through it.
+func TestZepOpenAILLMCallTimeoutIsFailoverEligible(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	llmConfig := &config.LLM{
+		OpenAIAPIKey:   "test-key",
+		OpenAIEndpoint: server.URL,
+		Model:          "gpt-3.5-turbo",
+	}
+
+	zllm, err := NewOpenAILLM(context.Background(), llmConfig)
+	if err != nil {
+		t.Fatalf("NewOpenAILLM: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, callErr := zllm.Call(ctx, "hello")
+	if callErr == nil {
+		t.Fatal("expected a timeout error from Call")
+	}
+	assert.True(
+		t,
+		shouldFailover(callErr),
+		"expected a ctx-timeout error from Call to be failover-eligible, got %v",
+		callErr,
+	)
+}
+
+func TestFallbackLLMGetTokenCountUsesPrimaryProvider(t *testing.T) {
+	primary := &fakeZepLLM{tokenCount: 42}
+	secondary := &fakeZepLLM{tokenCount: 7}
+
+	fb := &FallbackLLM{
+		providers: []models.ZepLLM{primary, secondary},
+		names:     []string{"openai/gpt-4", "anthropic/claude-instant-1"},
+	}
+
+	count, err := fb.GetTokenCount("hello")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, count)
+}
@@ -3,6 +3,8 @@ package llms
 import (
 	"context"
 	"log"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/tmc/langchaingo/schema"
@@ -31,11 +33,70 @@ func NewOpenAILLM(ctx context.Context, llmConfig *config.LLM) (*ZepOpenAILLM, er
 }
 
 type ZepOpenAILLM struct {
-	llm *openai.Chat
-	tkm *tiktoken.Tiktoken
+	llm       *openai.Chat
+	tkm       *tiktoken.Tiktoken
+	transport *statusCapturingTransport
+
+	// supportsNativeFunctions is true for real OpenAI and Azure OpenAI
+	// endpoints, which understand the `functions` API. A custom
+	// OpenAI-compatible endpoint -- e.g. a local llama.cpp server reached
+	// via newRegistryLLMClient's "local" backend -- may only implement the
+	// plain completions API, so CallWithFunctions falls back to prompting
+	// for a JSON object matching the schema instead.
+	supportsNativeFunctions bool
+}
+
+// statusCapturingTransport wraps the http.Client handed to langchaingo's
+// openai.Chat, recording the status code of the last response it saw.
+// langchaingo's client only ever returns its own wrapped error, never the
+// raw *http.Response, so this is how ZepOpenAILLM recovers enough
+// information to classify a failure as retryable for FallbackLLM -- see
+// statusCodeFailoverError in llm_fallback.go, which ZepZhiPuAILLM uses
+// directly since it makes its own HTTP requests.
+type statusCapturingTransport struct {
+	next http.RoundTripper
+
+	mu         sync.Mutex
+	lastStatus int
+}
+
+func (t *statusCapturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	t.mu.Lock()
+	if resp != nil {
+		t.lastStatus = resp.StatusCode
+	} else {
+		t.lastStatus = 0
+	}
+	t.mu.Unlock()
+
+	return resp, err
+}
+
+// reset clears the last observed status before a new request, so a stale
+// status from a previous call can't be misattributed to this one.
+func (t *statusCapturingTransport) reset() {
+	t.mu.Lock()
+	t.lastStatus = 0
+	t.mu.Unlock()
+}
+
+// classify wraps originalError as a RetryableLLMError if the last response
+// this transport saw was a 429 or 5xx, matching statusCodeFailoverError's
+// rule for the ZhiPuAI backend.
+func (t *statusCapturingTransport) classify(message string, originalError error) error {
+	t.mu.Lock()
+	status := t.lastStatus
+	t.mu.Unlock()
+
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return NewRetryableLLMError(message, status, originalError)
+	}
+	return NewLLMError(message, originalError)
 }
 
-func (zllm *ZepOpenAILLM) Init(_ context.Context, llmConfig *config.LLM) error {
+func (zllm *ZepOpenAILLM) Init(ctx context.Context, llmConfig *config.LLM) error {
 	// Initialize the Tiktoken client
 	encoding := "cl100k_base"
 	tkm, err := tiktoken.GetEncoding(encoding)
@@ -44,7 +105,7 @@ func (zllm *ZepOpenAILLM) Init(_ context.Context, llmConfig *config.LLM) error {
 	}
 	zllm.tkm = tkm
 
-	options, err := zllm.configureClient(llmConfig)
+	options, err := zllm.configureClient(ctx, llmConfig)
 	if err != nil {
 		return err
 	}
@@ -77,14 +138,121 @@ func (zllm *ZepOpenAILLM) Call(ctx context.Context,
 
 	messages := []schema.ChatMessage{schema.SystemChatMessage{Content: prompt}}
 
+	zllm.transport.reset()
 	completion, err := zllm.llm.Call(thisCtx, messages, options...)
 	if err != nil {
-		return "", err
+		return "", zllm.transport.classify("error calling OpenAI/Azure LLM", err)
 	}
 
 	return completion.GetContent(), nil
 }
 
+// CallStream behaves like Call but yields the completion incrementally on
+// the returned channel, one LLMChunk per token as langchaingo's streaming
+// callback delivers it. The channel is closed once the completion finishes;
+// on failure a single chunk with Err set is sent before the channel closes.
+func (zllm *ZepOpenAILLM) CallStream(ctx context.Context,
+	prompt string,
+	options ...llms.CallOption,
+) (<-chan LLMChunk, error) {
+	// If the LLM is not initialized, return an error
+	if zllm.llm == nil {
+		return nil, NewLLMError(InvalidLLMModelError, nil)
+	}
+
+	if len(options) == 0 {
+		options = append(options, llms.WithTemperature(DefaultTemperature))
+	}
+
+	chunks := make(chan LLMChunk)
+	options = append(options, llms.WithStreamingFunc(func(_ context.Context, delta []byte) error {
+		// If nothing is reading chunks anymore (ctx canceled, e.g. a
+		// disconnected SSE client), stop blocking on the send and abort the
+		// completion instead of leaking this goroutine forever.
+		select {
+		case chunks <- LLMChunk{Delta: string(delta)}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}))
+
+	messages := []schema.ChatMessage{schema.SystemChatMessage{Content: prompt}}
+
+	go func() {
+		defer close(chunks)
+
+		// No OpenAIAPITimeout here: a streamed completion may legitimately
+		// run longer than a blocking Call, so it's on the caller's ctx to
+		// carry whatever deadline applies.
+		_, err := zllm.llm.Call(ctx, messages, options...)
+		if err != nil {
+			select {
+			case chunks <- LLMChunk{Err: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		select {
+		case chunks <- LLMChunk{FinishReason: "stop"}:
+		case <-ctx.Done():
+		}
+	}()
+
+	return chunks, nil
+}
+
+// CallWithFunctions asks the model to respond by invoking one of functions,
+// using the OpenAI/Azure `functions` API, rather than by returning free-form
+// text. If the model declines to call a function, Content on the result
+// holds its reply instead. On a backend that doesn't speak the `functions`
+// API (see supportsNativeFunctions), it instead falls back to prompting for
+// a JSON object matching the schema, the same way ZepZhiPuAILLM does.
+func (zllm *ZepOpenAILLM) CallWithFunctions(ctx context.Context,
+	messages []schema.ChatMessage,
+	functions []FunctionSpec,
+	options ...llms.CallOption,
+) (FunctionCallResult, error) {
+	if zllm.llm == nil {
+		return FunctionCallResult{}, NewLLMError(InvalidLLMModelError, nil)
+	}
+
+	if !zllm.supportsNativeFunctions {
+		call := func(ctx context.Context, prompt string) (string, error) {
+			return zllm.Call(ctx, prompt, options...)
+		}
+		return callWithFunctionsPromptFallback(ctx, call, messages, functions)
+	}
+
+	thisCtx, cancel := context.WithTimeout(ctx, OpenAIAPITimeout)
+	defer cancel()
+
+	defs := make([]llms.FunctionDefinition, len(functions))
+	for i, fn := range functions {
+		defs[i] = llms.FunctionDefinition{
+			Name:        fn.Name,
+			Description: fn.Description,
+			Parameters:  fn.Parameters,
+		}
+	}
+	options = append(options, llms.WithFunctions(defs))
+
+	zllm.transport.reset()
+	completion, err := zllm.llm.Call(thisCtx, messages, options...)
+	if err != nil {
+		return FunctionCallResult{}, zllm.transport.classify("error calling OpenAI/Azure LLM with functions", err)
+	}
+
+	if completion.FunctionCall != nil {
+		return FunctionCallResult{
+			FunctionName: completion.FunctionCall.Name,
+			Arguments:    []byte(completion.FunctionCall.Arguments),
+		}, nil
+	}
+
+	return FunctionCallResult{Content: completion.GetContent()}, nil
+}
+
 func (zllm *ZepOpenAILLM) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
 	// If the LLM is not initialized, return an error
 	if zllm.llm == nil {
@@ -94,9 +262,10 @@ func (zllm *ZepOpenAILLM) EmbedTexts(ctx context.Context, texts []string) ([][]f
 	thisCtx, cancel := context.WithTimeout(ctx, OpenAIAPITimeout)
 	defer cancel()
 
+	zllm.transport.reset()
 	embeddings, err := zllm.llm.CreateEmbedding(thisCtx, texts)
 	if err != nil {
-		return nil, NewLLMError("error while creating embedding", err)
+		return nil, zllm.transport.classify("error while creating embedding", err)
 	}
 
 	return embeddings, nil
@@ -107,7 +276,7 @@ func (zllm *ZepOpenAILLM) GetTokenCount(text string) (int, error) {
 	return len(zllm.tkm.Encode(text, nil, nil)), nil
 }
 
-func (zllm *ZepOpenAILLM) configureClient(llmConfig *config.LLM) ([]openai.Option, error) {
+func (zllm *ZepOpenAILLM) configureClient(ctx context.Context, llmConfig *config.LLM) ([]openai.Option, error) {
 	// Retrieve the OpenAIAPIKey from configuration
 	apiKey := llmConfig.OpenAIAPIKey
 	// If the key is not set, log a fatal error and exit
@@ -118,12 +287,21 @@ func (zllm *ZepOpenAILLM) configureClient(llmConfig *config.LLM) ([]openai.Optio
 		log.Fatal("only one of AzureOpenAIEndpoint or OpenAIEndpoint can be set")
 	}
 
-	retryableHTTPClient := NewRetryableHTTPClient(MaxOpenAIAPIRequestAttempts, OpenAIAPITimeout)
+	retryableHTTPClient := NewRetryableHTTPClient(MaxOpenAIAPIRequestAttempts, OpenAIAPITimeout, inFallbackChain(ctx))
+	httpClient := retryableHTTPClient.StandardClient()
+	zllm.transport = &statusCapturingTransport{next: httpClient.Transport}
+	httpClient.Transport = zllm.transport
+
+	// A custom OpenAI-compatible endpoint (and not Azure, which has its own
+	// `functions` support) signals a non-OpenAI backend -- most commonly a
+	// local open-source model server -- that may not understand the
+	// `functions` API.
+	zllm.supportsNativeFunctions = llmConfig.OpenAIEndpoint == "" || llmConfig.AzureOpenAIEndpoint != ""
 
 	options := make([]openai.Option, 0)
 	options = append(
 		options,
-		openai.WithHTTPClient(retryableHTTPClient.StandardClient()),
+		openai.WithHTTPClient(httpClient),
 		openai.WithModel(llmConfig.Model),
 		openai.WithToken(apiKey),
 	)
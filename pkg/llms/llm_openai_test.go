@@ -0,0 +1,72 @@
+package llms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getzep/zep/config"
+)
+
+// TestZepOpenAILLMCallStreamStopsOnContextCancel exercises CallStream's
+// ctx-cancellation path: once the caller's ctx is canceled mid-stream, the
+// goroutine must end and close chunks within two seconds.
+func TestZepOpenAILLMCallStreamStopsOnContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("httptest server does not support flushing")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 100; i++ {
+			_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"x\"}}]}\n\n"))
+			flusher.Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	llmConfig := &config.LLM{
+		OpenAIAPIKey:   "test-key",
+		OpenAIEndpoint: server.URL,
+		Model:          "gpt-3.5-turbo",
+	}
+
+	zllm, err := NewOpenAILLM(context.Background(), llmConfig)
+	if err != nil {
+		t.Fatalf("NewOpenAILLM: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	chunks, err := zllm.CallStream(ctx, "hello")
+	if err != nil {
+		t.Fatalf("CallStream: %v", err)
+	}
+
+	// Read a single chunk to confirm the stream started, then walk away
+	// without draining the rest.
+	select {
+	case <-chunks:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for first chunk")
+	}
+	cancel()
+
+	// Drain whatever is left; the goroutine must close chunks promptly once
+	// it notices ctx is done.
+	closed := make(chan struct{})
+	go func() {
+		for range chunks {
+		}
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("CallStream goroutine did not exit after context cancellation")
+	}
+}
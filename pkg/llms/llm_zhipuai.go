@@ -0,0 +1,457 @@
+package llms
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/getzep/zep/config"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const ZhiPuAIAPITimeout = 90 * time.Second
+const ZhiPuAIAPIKeyNotSetError = "ZEP_ZHIPUAI_API_KEY is not set" //nolint:gosec
+const MaxZhiPuAIAPIRequestAttempts = 5
+const zhipuAITokenTTL = 3600 * time.Second
+
+const zhipuAIBaseURL = "https://open.bigmodel.cn/api/paas/v3/model-api"
+
+var _ models.ZepLLM = &ZepZhiPuAILLM{}
+
+var ValidZhiPuAILLMs = map[string]bool{
+	"chatglm_lite": true,
+	"chatglm_std":  true,
+	"chatglm_pro":  true,
+	"chatglm_turbo": true,
+	"glm-3-turbo":  true,
+	"glm-4":        true,
+	"charglm-3":    true,
+}
+
+// NewZhiPuAILLM constructs a ZepZhiPuAILLM from the `api_key.api_secret`
+// credential pair configured for the zhipuai service.
+func NewZhiPuAILLM(ctx context.Context, llmConfig *config.LLM) (*ZepZhiPuAILLM, error) {
+	zllm := &ZepZhiPuAILLM{}
+	err := zllm.Init(ctx, llmConfig)
+	if err != nil {
+		return nil, err
+	}
+	return zllm, nil
+}
+
+// ZepZhiPuAILLM implements models.ZepLLM for ZhiPuAI's ChatGLM family of
+// models, authenticating via a short-lived HS256 JWT built from the
+// `api_key.api_secret` credential pair ZhiPuAI issues.
+type ZepZhiPuAILLM struct {
+	model      string
+	apiKey     string
+	apiSecret  string
+	httpClient *http.Client
+
+	tokenMu     sync.Mutex
+	cachedToken string
+	tokenExpiry time.Time
+}
+
+func (zllm *ZepZhiPuAILLM) Init(ctx context.Context, llmConfig *config.LLM) error {
+	apiKey := llmConfig.ZhiPuAIAPIKey
+	if apiKey == "" {
+		return NewLLMError(ZhiPuAIAPIKeyNotSetError, nil)
+	}
+
+	key, secret, ok := strings.Cut(apiKey, ".")
+	if !ok {
+		return NewLLMError("ZEP_ZHIPUAI_API_KEY must be in the form api_key.api_secret", nil)
+	}
+
+	zllm.model = llmConfig.Model
+	zllm.apiKey = key
+	zllm.apiSecret = secret
+	zllm.httpClient = NewRetryableHTTPClient(MaxZhiPuAIAPIRequestAttempts, ZhiPuAIAPITimeout, inFallbackChain(ctx)).StandardClient()
+
+	return nil
+}
+
+// zhipuAIClaims is the JWT payload ZhiPuAI expects: an api_key plus an
+// exp/timestamp pair in epoch milliseconds, rather than the second-
+// resolution "exp" of RFC 7519 -- so it implements jwt.Claims itself
+// instead of embedding jwt.RegisteredClaims, whose ExpiresAt would clash
+// with our own Exp field under the same "exp" JSON key.
+type zhipuAIClaims struct {
+	APIKey    string `json:"api_key"`
+	Timestamp int64  `json:"timestamp"`
+	Exp       int64  `json:"exp"`
+}
+
+func (c zhipuAIClaims) Valid() error {
+	if time.Now().UnixMilli() > c.Exp {
+		return fmt.Errorf("zhipuai token is expired")
+	}
+	return nil
+}
+
+// token returns a cached HS256 JWT for the configured credentials, signing
+// and caching a fresh one once the previous token is within a minute of
+// expiring.
+func (zllm *ZepZhiPuAILLM) token() (string, error) {
+	zllm.tokenMu.Lock()
+	defer zllm.tokenMu.Unlock()
+
+	if zllm.cachedToken != "" && time.Now().Before(zllm.tokenExpiry.Add(-time.Minute)) {
+		return zllm.cachedToken, nil
+	}
+
+	now := time.Now()
+	expiry := now.Add(zhipuAITokenTTL)
+
+	claims := zhipuAIClaims{
+		APIKey:    zllm.apiKey,
+		Timestamp: now.UnixMilli(),
+		Exp:       expiry.UnixMilli(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["alg"] = "HS256"
+	token.Header["sign_type"] = "SIGN"
+
+	signed, err := token.SignedString([]byte(zllm.apiSecret))
+	if err != nil {
+		return "", NewLLMError("unable to sign zhipuai JWT", err)
+	}
+
+	zllm.cachedToken = signed
+	zllm.tokenExpiry = expiry
+
+	return signed, nil
+}
+
+type zhipuAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type zhipuAIChoice struct {
+	Content string `json:"content"`
+}
+
+type zhipuAIResponseData struct {
+	Choices []zhipuAIChoice `json:"choices"`
+}
+
+type zhipuAIResponse struct {
+	Code int                  `json:"code"`
+	Msg  string               `json:"msg"`
+	Data zhipuAIResponseData `json:"data"`
+}
+
+func (zllm *ZepZhiPuAILLM) doRequest(ctx context.Context, path string, body any, retryOn401 bool) ([]byte, error) {
+	token, err := zllm.token()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, NewLLMError("unable to marshal zhipuai request", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/%s", zhipuAIBaseURL, zllm.model, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, NewLLMError("unable to build zhipuai request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+
+	resp, err := zllm.httpClient.Do(req)
+	if err != nil {
+		return nil, NewLLMError("zhipuai request failed", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && retryOn401 {
+		// Force a fresh token -- ours may have been rejected as expired
+		// even though our local clock thought it still had headroom.
+		zllm.tokenMu.Lock()
+		zllm.cachedToken = ""
+		zllm.tokenMu.Unlock()
+		return zllm.doRequest(ctx, path, body, false)
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, NewLLMError("unable to read zhipuai response", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusCodeFailoverError(
+			fmt.Sprintf("zhipuai request returned status %d", resp.StatusCode),
+			resp,
+			nil,
+		)
+	}
+
+	return respBody, nil
+}
+
+func (zllm *ZepZhiPuAILLM) Call(ctx context.Context,
+	prompt string,
+	_ ...llms.CallOption,
+) (string, error) {
+	if zllm.httpClient == nil {
+		return "", NewLLMError(InvalidLLMModelError, nil)
+	}
+
+	thisCtx, cancel := context.WithTimeout(ctx, ZhiPuAIAPITimeout)
+	defer cancel()
+
+	body := map[string]any{
+		"prompt": []zhipuAIMessage{{Role: "user", Content: prompt}},
+	}
+
+	respBody, err := zllm.doRequest(thisCtx, "invoke", body, true)
+	if err != nil {
+		return "", err
+	}
+
+	var response zhipuAIResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return "", NewLLMError("unable to parse zhipuai response", err)
+	}
+	if len(response.Data.Choices) == 0 {
+		return "", NewLLMError("zhipuai response contained no choices", nil)
+	}
+
+	return response.Data.Choices[0].Content, nil
+}
+
+// openSSEStream issues the sse-invoke request and returns its response,
+// retrying once on a 401 with a freshly minted token -- mirroring
+// doRequest's retry-on-401 handling. Unlike doRequest, any other non-200
+// status is returned as-is rather than classified here: CallStream needs
+// the raw response body to read, and classifies the status as an LLMChunk
+// error once it has it.
+func (zllm *ZepZhiPuAILLM) openSSEStream(ctx context.Context, prompt string, retryOn401 bool) (*http.Response, error) {
+	token, err := zllm.token()
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]any{
+		"prompt": []zhipuAIMessage{{Role: "user", Content: prompt}},
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, NewLLMError("unable to marshal zhipuai request", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/%s", zhipuAIBaseURL, zllm.model, "sse-invoke")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, NewLLMError("unable to build zhipuai request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+
+	resp, err := zllm.httpClient.Do(req)
+	if err != nil {
+		return nil, NewLLMError("zhipuai request failed", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && retryOn401 {
+		resp.Body.Close()
+		// Force a fresh token -- ours may have been rejected as expired
+		// even though our local clock thought it still had headroom.
+		zllm.tokenMu.Lock()
+		zllm.cachedToken = ""
+		zllm.tokenMu.Unlock()
+		return zllm.openSSEStream(ctx, prompt, false)
+	}
+
+	return resp, nil
+}
+
+// CallStream streams the completion over ZhiPuAI's SSE endpoint, yielding
+// one LLMChunk per `event: add` message and closing the channel on the
+// terminal `event: finish` message.
+func (zllm *ZepZhiPuAILLM) CallStream(ctx context.Context,
+	prompt string,
+	_ ...llms.CallOption,
+) (<-chan LLMChunk, error) {
+	if zllm.httpClient == nil {
+		return nil, NewLLMError(InvalidLLMModelError, nil)
+	}
+
+	resp, err := zllm.openSSEStream(ctx, prompt, true)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan LLMChunk)
+
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+
+		// send delivers a chunk unless the caller has walked away (ctx
+		// canceled), so a stalled consumer can't leak this goroutine.
+		send := func(chunk LLMChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// A non-200 body isn't an event stream at all -- scanning it would
+		// just find no event:/data: lines and close chunks with nothing
+		// sent, leaving the SSE consumer looking at a stream that ended
+		// with no explanation. Surface it as a chunk error instead,
+		// classified the same way doRequest classifies a non-stream
+		// response.
+		if resp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(resp.Body)
+			send(LLMChunk{Err: statusCodeFailoverError(
+				fmt.Sprintf("zhipuai stream request returned status %d", resp.StatusCode),
+				resp,
+				fmt.Errorf("%s", respBody),
+			)})
+			return
+		}
+
+		scanner := newSSEScanner(resp.Body)
+		for scanner.Scan() {
+			event, data := scanner.Event()
+			switch event {
+			case "add":
+				if !send(LLMChunk{Delta: data}) {
+					return
+				}
+			case "finish":
+				send(LLMChunk{FinishReason: "stop"})
+				return
+			case "error":
+				send(LLMChunk{Err: NewLLMError("zhipuai stream error: "+data, nil)})
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			send(LLMChunk{Err: err})
+		}
+	}()
+
+	return chunks, nil
+}
+
+// CallWithFunctions has no native tool-calling support on ZhiPuAI, so it
+// falls back to prompting the model to reply with the function's arguments
+// as a bare JSON object matching its schema.
+func (zllm *ZepZhiPuAILLM) CallWithFunctions(ctx context.Context,
+	messages []schema.ChatMessage,
+	functions []FunctionSpec,
+	_ ...llms.CallOption,
+) (FunctionCallResult, error) {
+	call := func(ctx context.Context, prompt string) (string, error) {
+		return zllm.Call(ctx, prompt)
+	}
+	return callWithFunctionsPromptFallback(ctx, call, messages, functions)
+}
+
+func (zllm *ZepZhiPuAILLM) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	if zllm.httpClient == nil {
+		return nil, NewLLMError(InvalidLLMModelError, nil)
+	}
+
+	thisCtx, cancel := context.WithTimeout(ctx, ZhiPuAIAPITimeout)
+	defer cancel()
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		respBody, err := zllm.doRequest(thisCtx, "text_embedding", map[string]string{"prompt": text}, true)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Data struct {
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(respBody, &response); err != nil {
+			return nil, NewLLMError("unable to parse zhipuai embedding response", err)
+		}
+
+		embeddings[i] = response.Data.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// sseScanner walks a ZhiPuAI SSE stream, accumulating "event:"/"data:"
+// lines into (event, data) pairs delivered one per blank-line-terminated
+// block. Each data value is trimmed of surrounding whitespace, and several
+// "data:" lines in one block are concatenated with no newline between them.
+type sseScanner struct {
+	scanner *bufio.Scanner
+	event   string
+	data    strings.Builder
+	err     error
+}
+
+func newSSEScanner(r io.Reader) *sseScanner {
+	return &sseScanner{scanner: bufio.NewScanner(r)}
+}
+
+func (s *sseScanner) Scan() bool {
+	s.event = ""
+	s.data.Reset()
+
+	sawField := false
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			if sawField {
+				return true
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			s.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			sawField = true
+		case strings.HasPrefix(line, "data:"):
+			s.data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+			sawField = true
+		}
+	}
+
+	s.err = s.scanner.Err()
+	return sawField
+}
+
+func (s *sseScanner) Event() (event, data string) {
+	return s.event, s.data.String()
+}
+
+func (s *sseScanner) Err() error {
+	return s.err
+}
+
+// GetTokenCount approximates the token count for text. ZhiPuAI's ChatGLM
+// models aren't covered by tiktoken, so we fall back to a byte-length/4
+// heuristic rather than pulling in a GLM-specific tokenizer.
+func (zllm *ZepZhiPuAILLM) GetTokenCount(text string) (int, error) {
+	return len(text) / 4, nil
+}
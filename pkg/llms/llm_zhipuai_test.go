@@ -0,0 +1,112 @@
+package llms
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEScannerParsesAddAndFinishEvents(t *testing.T) {
+	stream := "event:add\ndata:hello\n\nevent:add\ndata: world\n\nevent:finish\ndata:\n\n"
+	scanner := newSSEScanner(strings.NewReader(stream))
+
+	var events []string
+	var data []string
+	for scanner.Scan() {
+		event, d := scanner.Event()
+		events = append(events, event)
+		data = append(data, d)
+	}
+
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"add", "add", "finish"}, events)
+	assert.Equal(t, []string{"hello", "world", ""}, data)
+}
+
+func TestSSEScannerAccumulatesMultipleDataLinesInOneBlock(t *testing.T) {
+	stream := "event:add\ndata:hello\ndata: world\n\n"
+	scanner := newSSEScanner(strings.NewReader(stream))
+
+	assert.True(t, scanner.Scan())
+	event, data := scanner.Event()
+	assert.Equal(t, "add", event)
+	assert.Equal(t, "helloworld", data)
+
+	assert.False(t, scanner.Scan())
+	assert.NoError(t, scanner.Err())
+}
+
+func TestSSEScannerIgnoresUnrecognizedLines(t *testing.T) {
+	stream := ": this is a comment\nevent:add\ndata:hello\n\n"
+	scanner := newSSEScanner(strings.NewReader(stream))
+
+	assert.True(t, scanner.Scan())
+	event, data := scanner.Event()
+	assert.Equal(t, "add", event)
+	assert.Equal(t, "hello", data)
+}
+
+func TestSSEScannerReturnsFalseOnEmptyStream(t *testing.T) {
+	scanner := newSSEScanner(strings.NewReader(""))
+	assert.False(t, scanner.Scan())
+	assert.NoError(t, scanner.Err())
+}
+
+func TestZhiPuAILLMTokenCachesUntilNearExpiry(t *testing.T) {
+	zllm := &ZepZhiPuAILLM{apiKey: "key", apiSecret: "secret"}
+
+	first, err := zllm.token()
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	second, err := zllm.token()
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	assert.Equal(t, first, second, "expected a cached token to be reused while still well within its TTL")
+}
+
+func TestZhiPuAILLMTokenRefreshesOnceNearExpiry(t *testing.T) {
+	zllm := &ZepZhiPuAILLM{apiKey: "key", apiSecret: "secret"}
+
+	first, err := zllm.token()
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	// Simulate the cached token being within a minute of expiry -- token()
+	// should mint and cache a new one rather than handing back the stale one.
+	zllm.tokenMu.Lock()
+	zllm.tokenExpiry = time.Now().Add(30 * time.Second)
+	zllm.tokenMu.Unlock()
+	time.Sleep(2 * time.Millisecond) // ensure the new token's embedded timestamp differs
+
+	second, err := zllm.token()
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	assert.NotEqual(t, first, second, "expected a near-expiry token to be refreshed")
+}
+
+func TestZhiPuAIClaimsValidRejectsExpiredToken(t *testing.T) {
+	claims := zhipuAIClaims{
+		APIKey:    "key",
+		Timestamp: time.Now().Add(-time.Hour).UnixMilli(),
+		Exp:       time.Now().Add(-time.Minute).UnixMilli(),
+	}
+	assert.Error(t, claims.Valid())
+}
+
+func TestZhiPuAIClaimsValidAcceptsUnexpiredToken(t *testing.T) {
+	claims := zhipuAIClaims{
+		APIKey:    "key",
+		Timestamp: time.Now().UnixMilli(),
+		Exp:       time.Now().Add(time.Hour).UnixMilli(),
+	}
+	assert.NoError(t, claims.Valid())
+}
@@ -0,0 +1,143 @@
+package llms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/getzep/zep/pkg/llms/embedpb"
+	"github.com/getzep/zep/pkg/models"
+)
+
+const LocalEmbedderTimeout = 30 * time.Second
+const LocalEmbedderDialTimeout = 5 * time.Second
+const MaxLocalEmbedderDialAttempts = 5
+
+// localEmbedderPool keeps one pooled gRPC connection per sidecar address,
+// so repeated EmbedTexts calls to the same sidecar reuse the connection
+// (and its HTTP/2 stream multiplexing) rather than dialing fresh each time.
+var localEmbedderPool = struct {
+	sync.Mutex
+	clients map[string]*LocalEmbedderClient
+}{clients: make(map[string]*LocalEmbedderClient)}
+
+// LocalEmbedderClient is a pooled gRPC client for the sentence-transformers
+// sidecar (see sidecar/server.py), used when an embedding model's Service
+// is "local".
+type LocalEmbedderClient struct {
+	conn   *grpc.ClientConn
+	client embedpb.EmbedderClient
+}
+
+// getLocalEmbedderClient returns the pooled client for address, dialing and
+// caching a new one on first use. Dialing retries with backoff since the
+// sidecar commonly starts after Zep during local/offline deployments.
+func getLocalEmbedderClient(address string) (*LocalEmbedderClient, error) {
+	localEmbedderPool.Lock()
+	defer localEmbedderPool.Unlock()
+
+	if client, ok := localEmbedderPool.clients[address]; ok {
+		return client, nil
+	}
+
+	var conn *grpc.ClientConn
+	var err error
+	for attempt := 0; attempt < MaxLocalEmbedderDialAttempts; attempt++ {
+		dialCtx, cancel := context.WithTimeout(context.Background(), LocalEmbedderDialTimeout)
+		conn, err = grpc.DialContext(
+			dialCtx,
+			address,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithBlock(),
+		)
+		cancel()
+		if err == nil {
+			break
+		}
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+	if err != nil {
+		return nil, NewLLMError(fmt.Sprintf("unable to dial local embedder sidecar at %q", address), err)
+	}
+
+	client := &LocalEmbedderClient{conn: conn, client: embedpb.NewEmbedderClient(conn)}
+	localEmbedderPool.clients[address] = client
+
+	return client, nil
+}
+
+// embedTextsLocal embeds text against the sentence-transformers sidecar
+// configured on model.Local, validating that the sidecar's reported
+// embedding dimension matches the configured one so a pgvector schema
+// mismatch fails here rather than at first insert.
+func embedTextsLocal(
+	ctx context.Context,
+	model *models.EmbeddingModel,
+	text []string,
+) ([][]float32, error) {
+	localConfig := model.Local
+
+	if localConfig.SidecarAddress == "" {
+		return nil, NewLLMError("local embeddings sidecar address is not configured", nil)
+	}
+
+	client, err := getLocalEmbedderClient(localConfig.SidecarAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	thisCtx, cancel := context.WithTimeout(ctx, LocalEmbedderTimeout)
+	defer cancel()
+
+	resp, err := client.client.Embed(thisCtx, &embedpb.EmbedRequest{Texts: text})
+	if err != nil {
+		return nil, NewLLMError("local embedder sidecar request failed", err)
+	}
+
+	if err := checkDimensions(localConfig.Model, resp.Dimensions, localConfig.Dimensions); err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		embeddings[i] = e.Values
+	}
+
+	return embeddings, nil
+}
+
+// checkDimensions validates a sidecar's reported embedding dimension against
+// the one configured on the model. A configured dimension of 0 means the
+// operator hasn't opted into the check.
+func checkDimensions(modelName string, reported int32, configured int) error {
+	if configured != 0 && int(reported) != configured {
+		return NewLLMError(
+			fmt.Sprintf(
+				"local embedder %q reported %d dimensions, configured for %d",
+				modelName,
+				reported,
+				configured,
+			),
+			nil,
+		)
+	}
+	return nil
+}
+
+// ValidateLocalEmbedder dials model's sidecar and performs a single probe
+// embedding, confirming the sidecar is reachable and that it reports the
+// dimension Zep is configured for. Call this once per local embedding model
+// during app startup (alongside the rest of Zep's config validation) so a
+// pgvector schema mismatch fails fast at boot rather than at first insert.
+func ValidateLocalEmbedder(ctx context.Context, model *models.EmbeddingModel) error {
+	if model == nil || model.Service != "local" {
+		return nil
+	}
+
+	_, err := embedTextsLocal(ctx, model, []string{"zep local embedder startup probe"})
+	return err
+}
@@ -0,0 +1,44 @@
+package llms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+func TestCheckDimensionsMatches(t *testing.T) {
+	err := checkDimensions("all-MiniLM-L6-v2", 384, 384)
+	assert.NoError(t, err)
+}
+
+func TestCheckDimensionsMismatch(t *testing.T) {
+	err := checkDimensions("all-MiniLM-L6-v2", 384, 768)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "384")
+	assert.Contains(t, err.Error(), "768")
+}
+
+func TestCheckDimensionsUnconfiguredSkipsValidation(t *testing.T) {
+	err := checkDimensions("all-MiniLM-L6-v2", 384, 0)
+	assert.NoError(t, err)
+}
+
+func TestValidateLocalEmbedderSkipsNonLocalModels(t *testing.T) {
+	model := &models.EmbeddingModel{Service: "openai"}
+
+	err := ValidateLocalEmbedder(context.Background(), model)
+	assert.NoError(t, err)
+}
+
+func TestValidateLocalEmbedderRequiresSidecarAddress(t *testing.T) {
+	model := &models.EmbeddingModel{
+		Service: "local",
+		Local:   models.LocalEmbeddingConfig{Dimensions: 384},
+	}
+
+	err := ValidateLocalEmbedder(context.Background(), model)
+	assert.Error(t, err)
+}
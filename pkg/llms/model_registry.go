@@ -0,0 +1,99 @@
+package llms
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelConfig is a single model entry loaded from a YAML file in the model
+// registry directory, one file per model, along the lines of LocalAI's
+// model config files.
+type ModelConfig struct {
+	Name        string   `yaml:"name"`
+	Backend     string   `yaml:"backend"` // openai, anthropic, azure, openai-compat, local
+	Deployment  string   `yaml:"deployment,omitempty"`
+	MaxTokens   int      `yaml:"max_tokens"`
+	Temperature float64  `yaml:"temperature"`
+	TopP        float64  `yaml:"top_p"`
+	Stop        []string `yaml:"stop,omitempty"`
+}
+
+// ModelRegistry is a directory-backed collection of ModelConfigs keyed by
+// model name.
+type ModelRegistry struct {
+	models map[string]*ModelConfig
+}
+
+// NewModelRegistry loads every *.yaml / *.yml file under dir into a
+// ModelRegistry. Each file declares exactly one model.
+func NewModelRegistry(dir string) (*ModelRegistry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read model registry dir %q: %w", dir, err)
+	}
+
+	registry := &ModelRegistry{models: make(map[string]*ModelConfig)}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read model config %q: %w", path, err)
+		}
+
+		model := &ModelConfig{}
+		if err := yaml.Unmarshal(data, model); err != nil {
+			return nil, fmt.Errorf("unable to parse model config %q: %w", path, err)
+		}
+		if model.Name == "" {
+			return nil, fmt.Errorf("model config %q is missing a name", path)
+		}
+
+		registry.models[model.Name] = model
+	}
+
+	return registry, nil
+}
+
+// Get returns the ModelConfig registered under name, and whether it exists.
+func (r *ModelRegistry) Get(name string) (*ModelConfig, bool) {
+	if r == nil {
+		return nil, false
+	}
+	model, ok := r.models[name]
+	return model, ok
+}
+
+// MaxTokens returns the configured context window for name, falling back to
+// the legacy MaxLLMTokensMap when the registry has no entry for it.
+func (r *ModelRegistry) MaxTokens(name string) (int, bool) {
+	if model, ok := r.Get(name); ok {
+		return model.MaxTokens, true
+	}
+	tokens, ok := MaxLLMTokensMap[name]
+	return tokens, ok
+}
+
+// defaultRegistry is the process-wide registry NewLLMClient, handleOpenAIClient,
+// and GetLLMModelName consult ahead of the static Valid*LLMs maps, which remain
+// as the fallback for deployments that don't configure a registry directory.
+//
+// Nothing in this tree calls NewModelRegistry to populate it yet -- there's no
+// startup/bootstrap package here to wire it from -- so defaultRegistry is
+// always nil today and the registry-backed dispatch paths that consult it are
+// unreachable in practice. Get's nil-receiver check makes that safe: every
+// lookup just falls through to the static maps. A future bootstrap package
+// should assign defaultRegistry from NewModelRegistry(dir) once at startup.
+var defaultRegistry *ModelRegistry
@@ -0,0 +1,50 @@
+package llms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModelRegistryLoadAndLookup(t *testing.T) {
+	dir := t.TempDir()
+	modelFile := filepath.Join(dir, "glm-4.yaml")
+	err := os.WriteFile(modelFile, []byte(`
+name: glm-4
+backend: zhipuai
+max_tokens: 128000
+temperature: 0.1
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := NewModelRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewModelRegistry: %v", err)
+	}
+
+	model, ok := registry.Get("glm-4")
+	if !ok {
+		t.Fatal("expected glm-4 to be registered")
+	}
+	if model.MaxTokens != 128000 {
+		t.Errorf("got MaxTokens %d, want 128000", model.MaxTokens)
+	}
+}
+
+func TestModelRegistryMaxTokensFallsBackToStaticMap(t *testing.T) {
+	registry := &ModelRegistry{models: map[string]*ModelConfig{}}
+	tokens, ok := registry.MaxTokens("gpt-4")
+	if !ok || tokens != MaxLLMTokensMap["gpt-4"] {
+		t.Errorf("expected fallback to static map, got (%d, %v)", tokens, ok)
+	}
+}
+
+func TestWithModelOverridesConfiguredModel(t *testing.T) {
+	options := &modelOptions{}
+	WithModel("glm-4")(options)
+	if options.model != "glm-4" {
+		t.Errorf("got %q, want %q", options.model, "glm-4")
+	}
+}
@@ -0,0 +1,85 @@
+package llms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/getzep/zep/pkg/models"
+)
+
+// StreamingLLM is implemented by ZepLLM backends that support incremental
+// output -- currently ZepOpenAILLM and ZepZhiPuAILLM. It's kept separate
+// from models.ZepLLM because not every backend can stream.
+type StreamingLLM interface {
+	CallStream(ctx context.Context, prompt string, options ...llms.CallOption) (<-chan LLMChunk, error)
+}
+
+// sseChunk is the JSON shape of one `data:` line, a plain-field mirror of
+// LLMChunk since LLMChunk.Err is an interface and doesn't marshal usefully
+// on its own.
+type sseChunk struct {
+	Delta        string `json:"delta,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// StreamCallHandler returns an http.HandlerFunc that streams llm's
+// completion for the `prompt` query parameter as Server-Sent Events, one
+// `data:` line per LLMChunk. Mount it on the extractor routes that want to
+// expose summarizer / intent extractor output in real time instead of
+// waiting for the full completion.
+func StreamCallHandler(llm models.ZepLLM) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		streaming, ok := llm.(StreamingLLM)
+		if !ok {
+			http.Error(w, "configured LLM does not support streaming", http.StatusNotImplemented)
+			return
+		}
+
+		prompt := r.URL.Query().Get("prompt")
+		if prompt == "" {
+			http.Error(w, "missing \"prompt\" query parameter", http.StatusBadRequest)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		chunks, err := streaming.CallStream(r.Context(), prompt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		for chunk := range chunks {
+			out := sseChunk{Delta: chunk.Delta, FinishReason: chunk.FinishReason}
+			if chunk.Err != nil {
+				out.Error = chunk.Err.Error()
+			}
+
+			payload, err := json.Marshal(out)
+			if err != nil {
+				log.Errorf("unable to marshal LLMChunk for SSE: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+				// The client disconnected; CallStream's goroutine notices
+				// r.Context() is done and unwinds on its own.
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}